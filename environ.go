@@ -7,11 +7,14 @@
 package environ
 
 import (
+	"encoding"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -24,33 +27,369 @@ const (
 // LookupEnvironmentFunc is an interface to allow swapping out of os.LookupEnv
 type LookupEnvironmentFunc func(string) (string, bool)
 
+// Decoder is the interface implemented by types which know how to populate
+// themselves from a raw environment variable value. If a struct field's type
+// (or a pointer to it) implements Decoder, UnmarshalEnvironment will call
+// EnvironDecode instead of applying its built-in reflect.Kind based
+// conversion. This lets callers bind arbitrary types (URLs, IP addresses,
+// custom enums, time.Duration, ...) without needing changes to this package.
+//
+// encoding.TextUnmarshaler is also honoured as a Decoder, so types which
+// already support text (un)marshalling for other reasons work here for free.
+type Decoder interface {
+	EnvironDecode(value string) error
+}
+
+// Unmarshaler is implemented by types which want to take over populating an
+// entire struct field from the environment themselves, rather than having
+// UnmarshalEnvironment recurse into its fields. If a struct field's type (or
+// a pointer to it) implements Unmarshaler, UnmarshalEnviron is called with a
+// LookupEnvironmentFunc scoped to that field (including any "prefix="
+// applied to it) instead of walking the struct.
+type Unmarshaler interface {
+	UnmarshalEnviron(lookupenv LookupEnvironmentFunc) error
+}
+
+// FieldError is returned when a struct field could not be decoded from its
+// environment variable, and names the field and variable responsible so
+// callers can report useful diagnostics.
+type FieldError struct {
+	Field               string
+	EnvironmentVariable string
+	Err                 error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("environ: field %s (env %s): %s", e.Field, e.EnvironmentVariable, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// EnvironErrors is returned by UnmarshalEnvironment when one or more struct
+// fields could not be populated. Unlike a single trailing error, it collects
+// every failing field encountered in a pass, so callers can report all of
+// them at once instead of only the last one reached.
+type EnvironErrors []FieldError
+
+func (e EnvironErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap allows errors.Is and errors.As to reach any individual FieldError.
+func (e EnvironErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i := range e {
+		errs[i] = &e[i]
+	}
+	return errs
+}
+
+// Missing returns the environment variable names of every "required" field
+// which was not set and had no default.
+func (e EnvironErrors) Missing() []string {
+	var missing []string
+	for _, fe := range e {
+		if errors.Is(fe.Err, errRequired) {
+			missing = append(missing, fe.EnvironmentVariable)
+		}
+	}
+	return missing
+}
+
+var (
+	decoderType         = reflect.TypeOf((*Decoder)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// customDecoder checks whether field (or a pointer to it) implements Decoder
+// or encoding.TextUnmarshaler, checking the field's address before its value
+// so that pointer-receiver methods are found, and returns a function which
+// decodes a raw value into it. It returns nil if neither is implemented, so
+// that callers can fall back to other handling for the field.
+//
+// If field is itself a nil pointer whose type implements one of the
+// interfaces (e.g. a `Foo *net.IP` field), the type is checked statically
+// before the nil field.Interface() is ever asserted, and the pointee is
+// allocated via reflect.New so the returned function has somewhere to
+// decode into instead of dereferencing a nil pointer.
+func customDecoder(field reflect.Value) func(value string) error {
+	if field.Kind() == reflect.Ptr && field.IsNil() {
+		if !field.Type().Implements(decoderType) && !field.Type().Implements(textUnmarshalerType) {
+			return nil
+		}
+		if !field.CanSet() {
+			return nil
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+	}
+	if field.CanAddr() {
+		if d, ok := field.Addr().Interface().(Decoder); ok {
+			return d.EnvironDecode
+		}
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return func(value string) error { return u.UnmarshalText([]byte(value)) }
+		}
+	}
+	if d, ok := field.Interface().(Decoder); ok {
+		return d.EnvironDecode
+	}
+	if u, ok := field.Interface().(encoding.TextUnmarshaler); ok {
+		return func(value string) error { return u.UnmarshalText([]byte(value)) }
+	}
+	return nil
+}
+
+// hasCustomDecoder reports whether customDecoder would find a Decoder or
+// encoding.TextUnmarshaler for field, without its side effect of allocating
+// a nil pointer field. Callers that only need to classify a field, such as
+// collectRenderFields deciding whether to recurse into it, should use this
+// instead of customDecoder, since a nil pointer field they merely inspect
+// should stay nil.
+func hasCustomDecoder(field reflect.Value) bool {
+	if field.Kind() == reflect.Ptr {
+		return field.Type().Implements(decoderType) || field.Type().Implements(textUnmarshalerType)
+	}
+	if field.CanAddr() {
+		addrType := field.Addr().Type()
+		if addrType.Implements(decoderType) || addrType.Implements(textUnmarshalerType) {
+			return true
+		}
+	}
+	return field.Type().Implements(decoderType) || field.Type().Implements(textUnmarshalerType)
+}
+
 // UnmarshalFromOS just reads the real environment variables and sets the state
 func UnmarshalFromOS(into interface{}) error {
 	return UnmarshalEnvironment(os.LookupEnv, into)
 }
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue converts raw into fieldVal according to fieldVal's own type,
+// covering the built-in types this package supports: bool, string, all
+// signed/unsigned integer widths, float32/float64, time.Duration, []byte,
+// arbitrary []T, and map[K]V. Any other kind (complex numbers, arrays,
+// uintptr, interfaces, ...) is rejected as unsupported. Slice and map elements
+// are parsed by recursing back into setValue, so a new element type is
+// supported everywhere for free. stv supplies the AnyValTrue, Separator and
+// KVSeparator options which affect how a value is split.
+func setValue(fieldVal reflect.Value, raw string, stv StateVar) error {
+	t := fieldVal.Type()
+
+	// time.Duration's Kind is Int64, so it must be special-cased ahead of
+	// the generic integer case below.
+	if t == durationType {
+		if len(raw) == 0 {
+			fieldVal.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		fieldVal.SetInt(int64(d))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if stv.AnyValTrue {
+			// Force the value to true because the environment variable is set
+			fieldVal.SetBool(true)
+			return nil
+		}
+		if len(raw) == 0 {
+			fieldVal.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		fieldVal.SetBool(b)
+		return nil
+	case reflect.String:
+		fieldVal.SetString(raw)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if len(raw) == 0 {
+			fieldVal.SetInt(0)
+			return nil
+		}
+		xi, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		fieldVal.SetInt(xi)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if len(raw) == 0 {
+			fieldVal.SetUint(0)
+			return nil
+		}
+		xu, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return err
+		}
+		fieldVal.SetUint(xu)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if len(raw) == 0 {
+			fieldVal.SetFloat(0)
+			return nil
+		}
+		xf, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return err
+		}
+		fieldVal.SetFloat(xf)
+		return nil
+	case reflect.Slice:
+		return setSlice(fieldVal, raw, stv)
+	case reflect.Map:
+		return setMap(fieldVal, raw, stv)
+	default:
+		return fmt.Errorf("environ: unsupported type %s", t)
+	}
+}
+
+// setSlice fills fieldVal, a []T, by splitting raw on stv's separator (","
+// by default) and parsing each element as a T via setValue. []byte is
+// special-cased to take raw as-is rather than being split.
+func setSlice(fieldVal reflect.Value, raw string, stv StateVar) error {
+	t := fieldVal.Type()
+	if t.Elem().Kind() == reflect.Uint8 {
+		fieldVal.SetBytes([]byte(raw))
+		return nil
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	sep := stv.Separator
+	if sep == "" {
+		sep = defaultSliceSeparator
+	}
+	parts := strings.Split(raw, sep)
+	out := reflect.MakeSlice(t, len(parts), len(parts))
+	for i, part := range parts {
+		if err := setValue(out.Index(i), part, stv); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	fieldVal.Set(out)
+	return nil
+}
+
+// setMap fills fieldVal, a map[K]V, by splitting raw on stv's separator
+// into "key<kvseparator>value" pairs (":" by default) and parsing each side
+// as a K or V via setValue.
+func setMap(fieldVal reflect.Value, raw string, stv StateVar) error {
+	t := fieldVal.Type()
+	if len(raw) == 0 {
+		return nil
+	}
+	sep := stv.Separator
+	if sep == "" {
+		sep = defaultSliceSeparator
+	}
+	kvsep := stv.KVSeparator
+	if kvsep == "" {
+		kvsep = defaultMapKVSeparator
+	}
+	out := reflect.MakeMap(t)
+	for _, pair := range strings.Split(raw, sep) {
+		rawKey, rawVal, ok := strings.Cut(pair, kvsep)
+		if !ok {
+			return fmt.Errorf("environ: invalid map entry %q, want key%svalue", pair, kvsep)
+		}
+		keyVal := reflect.New(t.Key()).Elem()
+		if err := setValue(keyVal, rawKey, stv); err != nil {
+			return fmt.Errorf("key %q: %w", rawKey, err)
+		}
+		valVal := reflect.New(t.Elem()).Elem()
+		if err := setValue(valVal, rawVal, stv); err != nil {
+			return fmt.Errorf("value for key %q: %w", rawKey, err)
+		}
+		out.SetMapIndex(keyVal, valVal)
+	}
+	fieldVal.Set(out)
+	return nil
+}
+
 // StateVar is a processed struct tag of a parsed environment variable field
 type StateVar struct {
 	EnvironmentVariable string
 	Redact              bool
 	AnyValTrue          bool
+	Required            bool
+	Default             *string
+	Prefix              string
+	Separator           string
+	KVSeparator         string
 }
 
+const (
+	defaultSliceSeparator = ","
+	defaultMapKVSeparator = ":"
+)
+
+// errRequired is the sentinel error wrapped by the FieldError added for a
+// "required" field which was not set in the environment. EnvironErrors.Missing
+// uses it to tell required-but-unset fields apart from other failures.
+var errRequired = errors.New("required environment variable is not set")
+
 // parseStateVar is an internal function used to split the Struct tag used in
-// this package to allow custom options to be set (e.g redact, anyvaltrue)
-func parseStateVar(t reflect.StructTag) (sv StateVar) {
-	parts := strings.Split(t.Get(structFieldTagKey), ",")
-	sv.EnvironmentVariable = parts[0]
-	// panic(t.Get(structFieldTagKey)[0])
-	for _, v := range parts[1:] {
-		switch v {
+// this package to allow custom options to be set (e.g redact, anyvaltrue,
+// required, default=VALUE).
+//
+// The tag is split on the first comma to separate the environment variable
+// name from its options. Remaining options are themselves comma separated
+// and may carry an argument after an "=", except for default=, whose
+// argument may itself contain commas: once that option is reached, the rest
+// of the tag is taken verbatim as its value.
+func parseStateVar(t reflect.StructTag) (StateVar, error) {
+	var sv StateVar
+	name, rest, _ := strings.Cut(t.Get(structFieldTagKey), structRunnerTagsSeparator)
+	sv.EnvironmentVariable = name
+	for rest != "" {
+		var opt string
+		if idx := strings.Index(rest, structRunnerTagsSeparator); idx == -1 {
+			opt, rest = rest, ""
+		} else {
+			opt, rest = rest[:idx], rest[idx+1:]
+		}
+		key, value, hasValue := strings.Cut(opt, "=")
+		switch key {
 		case "redact":
 			sv.Redact = true
 		case "anyvaltrue":
 			sv.AnyValTrue = true
+		case "required":
+			sv.Required = true
+		case "default":
+			if hasValue && rest != "" {
+				value = value + structRunnerTagsSeparator + rest
+				rest = ""
+			}
+			sv.Default = &value
+		case "prefix":
+			sv.Prefix = value
+		case "separator":
+			sv.Separator = value
+		case "kvseparator":
+			sv.KVSeparator = value
 		}
 	}
-	return
+	if sv.Required && sv.Default != nil {
+		return sv, fmt.Errorf("environ: field tag for %q cannot combine required and default", sv.EnvironmentVariable)
+	}
+	return sv, nil
 }
 
 // UnmarshalEnvironment does the work of converting environment variables into
@@ -67,18 +406,59 @@ func parseStateVar(t reflect.StructTag) (sv StateVar) {
 //
 // In a few situations, UnmarshalEnvironment may fail to set a struct field
 // if the parsing of the string environment variable into the native type
-// fails. In this case the struct field will be left untouched. One error
-// may be returned which would be the last error reached when parsing the
-// environment.
+// fails. In this case the struct field will be left untouched. Every field
+// which fails is collected into the returned EnvironErrors, rather than only
+// the last one reached.
 //
-// Two struct tag options are available to modify the parsing behaviour:
+// Struct tag options available to modify the parsing behaviour:
 //
 // Option "redact" means that the value of the variable will be masked
-// when using environ.ToString.
+// when using environ.Render.
 //
 // Option "anyvaltrue" means that if the variable is set at all, and of type
 // boolean, then the struct field will be set to true (usually the value would
 // be parsed with strconv.ParseBool).
+//
+// Option "required" means that UnmarshalEnvironment will add the field to
+// the returned EnvironErrors (see EnvironErrors.Missing) if the variable is
+// unset. It cannot be combined with "default".
+//
+// Option "default=VALUE" supplies VALUE to use in place of the variable when
+// it is unset. VALUE is run through the same conversion as a real value, so
+// it must be valid for the field's type. It cannot be combined with
+// "required".
+//
+// Option "prefix=PREFIX" applies only to nested struct fields recursed into
+// by this function, and wraps lookupenv in PrefixLookuper for that field so
+// every variable name it reads is prefixed with PREFIX.
+//
+// Option "separator=SEP" sets the delimiter used to split a []T field's
+// value into elements (default ","). It is ignored by []byte, which takes
+// the raw value as-is.
+//
+// Option "kvseparator=SEP" sets the delimiter used between the key and
+// value of each entry of a map[K]V field's "key:value,key:value" value
+// (default ":").
+//
+// Fields support these built-in types: bool, string, all signed/unsigned
+// integer widths, float32/float64, time.Duration, []byte, arbitrary []T, and
+// map[K]V, with slice/map elements parsed through the same type dispatch as
+// a top-level scalar field. A field of any other kind (complex numbers,
+// arrays, uintptr, interfaces, ...) gets an unsupported type error when its
+// variable is set.
+//
+// Before falling back to its built-in reflect.Kind based conversion,
+// UnmarshalEnvironment checks whether the field's type (or a pointer to it)
+// implements Decoder or encoding.TextUnmarshaler, and if so calls that
+// method with the raw value instead. A failure returned from either is
+// wrapped in a *FieldError naming the field and environment variable.
+//
+// A struct field whose type is itself a struct, or a pointer to one, is
+// recursed into rather than decoded directly: nil pointers are allocated
+// first, and if the field's type (or a pointer to it) implements
+// Unmarshaler, UnmarshalEnviron is called instead of walking its fields.
+// Recursion tracks the struct types on its current path and fails with an
+// error rather than overflowing the stack if it finds a cycle.
 func UnmarshalEnvironment(lookupenv LookupEnvironmentFunc, into interface{}) error {
 	rv := reflect.ValueOf(into)
 	if rv.Kind() != reflect.Ptr {
@@ -87,85 +467,126 @@ func UnmarshalEnvironment(lookupenv LookupEnvironmentFunc, into interface{}) err
 	if rv.IsNil() {
 		return fmt.Errorf("Decode of nil %s", reflect.TypeOf(into))
 	}
+	return unmarshalStruct(lookupenv, into, map[reflect.Type]bool{}, nil)
+}
+
+// UnmarshalEnvironmentWith behaves like UnmarshalEnvironment, except that
+// every value read from lookupenv is first run through mutators, in order,
+// before type conversion. Each mutator sees the previous one's output, and
+// the pipeline stops at the first error, which is wrapped in a *FieldError
+// naming the field and environment variable it came from. Mutators apply
+// recursively to nested struct fields too.
+func UnmarshalEnvironmentWith(lookupenv LookupEnvironmentFunc, into interface{}, mutators ...Mutator) error {
+	rv := reflect.ValueOf(into)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("Decode of non-pointer %s", reflect.TypeOf(into))
+	}
+	if rv.IsNil() {
+		return fmt.Errorf("Decode of nil %s", reflect.TypeOf(into))
+	}
+	return unmarshalStruct(lookupenv, into, map[reflect.Type]bool{}, mutators)
+}
+
+// unmarshalStruct does the actual work behind UnmarshalEnvironment. seen
+// tracks the struct types already being populated on the current recursion
+// path, so that a struct which (directly or indirectly) contains itself is
+// reported as an error instead of recursing forever. mutators, if non-empty,
+// is run against every looked-up value before it reaches type conversion.
+func unmarshalStruct(lookupenv LookupEnvironmentFunc, into interface{}, seen map[reflect.Type]bool, mutators []Mutator) error {
 	st := reflect.TypeOf(into).Elem()
+	if seen[st] {
+		return fmt.Errorf("environ: cycle detected recursing into %s", st)
+	}
+	seen[st] = true
+	defer delete(seen, st)
+
 	sv := reflect.ValueOf(into).Elem()
-	var err error
+	var errs EnvironErrors
 	for i := 0; i < st.NumField(); i++ {
 		svf := st.Field(i)
-		stv := parseStateVar(svf.Tag)
-		if svf.Type.Kind() == reflect.Ptr {
-			// Need to recurse into pointer
-			log.Debugf("from %s recursing into field %d: %s", st, i, svf.Type)
-			UnmarshalEnvironment(lookupenv, sv.Field(i).Interface())
+		stv, perr := parseStateVar(svf.Tag)
+		if perr != nil {
+			errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: perr})
+			continue
 		}
-		if eval, isset := lookupenv(stv.EnvironmentVariable); isset {
-			log.Debugf("environment variable is set: %s", stv.EnvironmentVariable)
-			t := svf.Type
-			switch {
-			case t.Kind() == reflect.Bool:
-				if stv.AnyValTrue {
-					// Force the value to true because the environment variable is set
-					sv.Field(i).SetBool(true)
-				} else {
-					if len(eval) == 0 {
-						log.Tracef("value set with zero length, setting to false")
-						sv.Field(i).SetBool(false)
-					} else {
-						var xb bool
-						xb, err = strconv.ParseBool(eval)
-						sv.Field(i).SetBool(xb)
-					}
+
+		eval, isset := lookupenv(stv.EnvironmentVariable)
+		if len(mutators) > 0 {
+			mval, mset, merr := runMutators(mutators, stv.EnvironmentVariable, eval, isset)
+			if merr != nil {
+				errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: merr})
+				continue
+			}
+			eval, isset = mval, mset
+		}
+		if !isset && stv.Default != nil {
+			// Synthesise the default through the same parsing path below so
+			// that type conversion stays consistent with a real value.
+			eval, isset = *stv.Default, true
+		}
+
+		if decoder := customDecoder(sv.Field(i)); decoder != nil {
+			if isset {
+				log.Debugf("environment variable is set: %s", stv.EnvironmentVariable)
+				if derr := decoder(eval); derr != nil {
+					errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: derr})
 				}
-			case t.Kind() == reflect.String:
-				sv.Field(i).SetString(eval)
-			case t == reflect.SliceOf(reflect.TypeOf("")):
-				if len(eval) != 0 {
-					parts := strings.Split(eval, structRunnerTagsSeparator)
-					sv.Field(i).Set(reflect.ValueOf(parts))
+			} else if stv.Required {
+				errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: errRequired})
+			} else {
+				log.Tracef("environment variable unset: %s", stv.EnvironmentVariable)
+			}
+			continue
+		}
+
+		isPtrToStruct := svf.Type.Kind() == reflect.Ptr && svf.Type.Elem().Kind() == reflect.Struct
+		isStruct := svf.Type.Kind() == reflect.Struct
+		if isPtrToStruct || isStruct {
+			log.Debugf("from %s recursing into field %d: %s", st, i, svf.Type)
+			nested := lookupenv
+			if stv.Prefix != "" {
+				nested = PrefixLookuper(stv.Prefix, lookupenv)
+			}
+			var target reflect.Value
+			if isPtrToStruct {
+				if sv.Field(i).IsNil() {
+					sv.Field(i).Set(reflect.New(svf.Type.Elem()))
+				}
+				target = sv.Field(i)
+			} else {
+				target = sv.Field(i).Addr()
+			}
+			if um, ok := target.Interface().(Unmarshaler); ok {
+				if uerr := um.UnmarshalEnviron(nested); uerr != nil {
+					errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: uerr})
 				}
-			case t.Kind() == reflect.Int:
-				if len(eval) == 0 {
-					sv.Field(i).SetInt(0)
+				continue
+			}
+			if rerr := unmarshalStruct(nested, target.Interface(), seen, mutators); rerr != nil {
+				if ee, ok := rerr.(EnvironErrors); ok {
+					errs = append(errs, ee...)
 				} else {
-					var xi int
-					xi, err = strconv.Atoi(eval)
-					sv.Field(i).SetInt(int64(xi))
+					errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: rerr})
 				}
-			case t.Kind() == reflect.Struct:
-				panic("struct")
-			default:
-				log.Fatalf("unimplemented type: %s", t)
 			}
-		} else {
-			log.Tracef("environment variable unset: %s", stv.EnvironmentVariable)
+			continue
 		}
-	}
-	return err
-}
 
-// ToString returns a redacted representation of es
-func ToString(es interface{}) string {
-	st := reflect.TypeOf(es)
-	sb := strings.Builder{}
-	sb.WriteString("{ ")
-	for i := 0; i < st.NumField(); i++ {
-		svf := st.Field(i)
-		stv := parseStateVar(svf.Tag)
-		val := reflect.ValueOf(es).Field(i).Interface()
-		if stv.Redact {
-			switch svf.Type.Kind() {
-			case reflect.String:
-				// val = strings.Repeat("*", len(val.(string)))
-				if len(val.(string)) > 0 {
-					// I considered this being like strings.Repeat("*", len(val)) but we shouldn't expose the length of the password
-					val = "********"
-				} else {
-					val = ""
-				}
+		if isset {
+			log.Debugf("environment variable is set: %s", stv.EnvironmentVariable)
+			if serr := setValue(sv.Field(i), eval, stv); serr != nil {
+				errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: serr})
+			}
+		} else {
+			if stv.Required {
+				errs = append(errs, FieldError{Field: svf.Name, EnvironmentVariable: stv.EnvironmentVariable, Err: errRequired})
+			} else {
+				log.Tracef("environment variable unset: %s", stv.EnvironmentVariable)
 			}
 		}
-		sb.WriteString(fmt.Sprintf(`%s:%v `, svf.Name, val))
 	}
-	sb.WriteRune('}')
-	return sb.String()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
 }
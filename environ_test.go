@@ -1,8 +1,12 @@
 package environ
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestBasicDeepEqual(t *testing.T) {
@@ -161,7 +165,7 @@ func TestUnmarshalEnvironment(t *testing.T) {
 	}
 }
 
-func TestToString(t *testing.T) {
+func TestRenderPlain(t *testing.T) {
 	type args struct {
 		es interface{}
 	}
@@ -203,9 +207,189 @@ func TestToString(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ToString(tt.args.es); got != tt.want {
-				t.Errorf("ToString() = %v, want %v", got, tt.want)
+			got, err := Render(tt.args.es)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+// upperCSV is a test-only type which implements Decoder by upper-casing its
+// input, and errors if given an empty value.
+type upperCSV struct {
+	Value string
+}
+
+func (u *upperCSV) EnvironDecode(value string) error {
+	if value == "" {
+		return fmt.Errorf("upperCSV: empty value")
+	}
+	u.Value = strings.ToUpper(value)
+	return nil
+}
+
+// textDuration is a test-only type which implements
+// encoding.TextUnmarshaler by delegating to time.ParseDuration.
+type textDuration struct {
+	time.Duration
+}
+
+func (d *textDuration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+type DecoderEnv struct {
+	Upper   upperCSV     `environ:"UPPER"`
+	Timeout textDuration `environ:"TIMEOUT"`
+}
+
+func TestUnmarshalEnvironmentDecoder(t *testing.T) {
+	t.Run("calls EnvironDecode and UnmarshalText", func(t *testing.T) {
+		got := DecoderEnv{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"UPPER":   "hello",
+			"TIMEOUT": "5s",
+		}), &got)
+		if err != nil {
+			t.Fatalf("UnmarshalEnvironment() error = %v", err)
+		}
+		if got.Upper.Value != "HELLO" {
+			t.Errorf("Upper.Value = %q, want %q", got.Upper.Value, "HELLO")
+		}
+		if got.Timeout.Duration != 5*time.Second {
+			t.Errorf("Timeout.Duration = %v, want %v", got.Timeout.Duration, 5*time.Second)
+		}
+	})
+
+	t.Run("wraps a decode failure in a FieldError", func(t *testing.T) {
+		got := DecoderEnv{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"UPPER":   "",
+			"TIMEOUT": UnsetEnvPlaceholder,
+		}), &got)
+		var fe *FieldError
+		if !errors.As(err, &fe) {
+			t.Fatalf("UnmarshalEnvironment() error = %v, want *FieldError", err)
+		}
+		if fe.Field != "Upper" || fe.EnvironmentVariable != "UPPER" {
+			t.Errorf("FieldError = %+v, want Field=Upper EnvironmentVariable=UPPER", fe)
+		}
+	})
+
+	t.Run("allocates a nil *T field before decoding into it", func(t *testing.T) {
+		type NilDecoderEnv struct {
+			Timeout *textDuration `environ:"TIMEOUT"`
+		}
+		got := NilDecoderEnv{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"TIMEOUT": "5s",
+		}), &got)
+		if err != nil {
+			t.Fatalf("UnmarshalEnvironment() error = %v", err)
+		}
+		if got.Timeout == nil {
+			t.Fatal("Timeout was not allocated")
+		}
+		if got.Timeout.Duration != 5*time.Second {
+			t.Errorf("Timeout.Duration = %v, want %v", got.Timeout.Duration, 5*time.Second)
+		}
+	})
+}
+
+type RequiredDefaultEnv struct {
+	Host string `environ:"HOST,required"`
+	Port int    `environ:"PORT,default=8080"`
+	Note string `environ:"NOTE,default=a,b,c"`
+}
+
+func TestUnmarshalEnvironmentRequiredAndDefault(t *testing.T) {
+	t.Run("uses the default when unset", func(t *testing.T) {
+		got := RequiredDefaultEnv{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"HOST": "example.com",
+			"PORT": UnsetEnvPlaceholder,
+			"NOTE": UnsetEnvPlaceholder,
+		}), &got)
+		if err != nil {
+			t.Fatalf("UnmarshalEnvironment() error = %v", err)
+		}
+		want := RequiredDefaultEnv{Host: "example.com", Port: 8080, Note: "a,b,c"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UnmarshalEnvironment() = \n%+v, want \n%+v", got, want)
+		}
+	})
+
+	t.Run("collects a required-but-unset field into Missing", func(t *testing.T) {
+		got := RequiredDefaultEnv{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"HOST": UnsetEnvPlaceholder,
+			"PORT": UnsetEnvPlaceholder,
+			"NOTE": UnsetEnvPlaceholder,
+		}), &got)
+		var ee EnvironErrors
+		if !errors.As(err, &ee) {
+			t.Fatalf("UnmarshalEnvironment() error = %v, want EnvironErrors", err)
+		}
+		if want := []string{"HOST"}; !reflect.DeepEqual(ee.Missing(), want) {
+			t.Errorf("EnvironErrors.Missing() = %v, want %v", ee.Missing(), want)
+		}
+	})
+
+	t.Run("aggregates every failing field, not just the last", func(t *testing.T) {
+		type twoBadInts struct {
+			A int `environ:"A"`
+			B int `environ:"B"`
+		}
+		got := twoBadInts{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"A": "not-an-int",
+			"B": "also-not-an-int",
+		}), &got)
+		var ee EnvironErrors
+		if !errors.As(err, &ee) {
+			t.Fatalf("UnmarshalEnvironment() error = %v, want EnvironErrors", err)
+		}
+		if len(ee) != 2 {
+			t.Fatalf("EnvironErrors has %d entries, want 2: %v", len(ee), ee)
+		}
+	})
+
+	t.Run("applies prefix when recursing into a nested pointer struct", func(t *testing.T) {
+		type DBConfig struct {
+			Host string `environ:"HOST"`
+		}
+		type App struct {
+			DB *DBConfig `environ:",prefix=DB_"`
+		}
+		got := App{DB: &DBConfig{}}
+		err := UnmarshalEnvironment(MapLookuper(map[string]string{"DB_HOST": "localhost"}), &got)
+		if err != nil {
+			t.Fatalf("UnmarshalEnvironment() error = %v", err)
+		}
+		if got.DB.Host != "localhost" {
+			t.Errorf("DB.Host = %q, want %q", got.DB.Host, "localhost")
+		}
+	})
+
+	t.Run("rejects required combined with default", func(t *testing.T) {
+		type badTag struct {
+			A string `environ:"A,required,default=x"`
+		}
+		got := badTag{}
+		err := UnmarshalEnvironment(FakeLookupEnv(map[string]string{
+			"A": UnsetEnvPlaceholder,
+		}), &got)
+		if err == nil {
+			t.Fatal("UnmarshalEnvironment() error = nil, want a tag parse error")
+		}
+	})
+}
@@ -24,6 +24,9 @@ func (f fakeEnv) lookupEnv(k string) (string, bool) {
 // zero-length value in a Go map, there exists a special marker value (stored)
 // in UnsetEnvPlaceholder which marks the value as being unset in the
 // environment.
+//
+// Outside of tests, prefer MapLookuper, which reports a key absent from the
+// map as unset rather than relying on a sentinel value.
 func FakeLookupEnv(input map[string]string) LookupEnvironmentFunc {
 	f := fakeEnv{vals: input}
 	return f.lookupEnv
@@ -32,7 +35,7 @@ func FakeLookupEnv(input map[string]string) LookupEnvironmentFunc {
 // FakeEmptyEnvironment is an implementation of LookupEnvironmentFunc which
 // always pretends that the requested value was not set in the environment.
 func FakeEmptyEnvironment() LookupEnvironmentFunc {
-	return func(string) (string,bool) {
-		return "",false
+	return func(string) (string, bool) {
+		return "", false
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,35 @@
+package environ
+
+// PrefixLookuper returns a LookupEnvironmentFunc which prepends prefix to
+// every key before querying base. Combined with the "prefix=" struct tag
+// option, this lets a nested config struct be read from variables like
+// DB_HOST while still being tagged with the plain name HOST.
+func PrefixLookuper(prefix string, base LookupEnvironmentFunc) LookupEnvironmentFunc {
+	return func(key string) (string, bool) {
+		return base(prefix + key)
+	}
+}
+
+// MapLookuper returns a LookupEnvironmentFunc backed by a plain
+// map[string]string, for supplying configuration from somewhere other than
+// the real environment. A key absent from vals is reported as unset.
+func MapLookuper(vals map[string]string) LookupEnvironmentFunc {
+	return func(key string) (string, bool) {
+		v, ok := vals[key]
+		return v, ok
+	}
+}
+
+// MultiLookuper returns a LookupEnvironmentFunc which consults each of
+// sources in order, returning the value from the first one which reports the
+// key as set.
+func MultiLookuper(sources ...LookupEnvironmentFunc) LookupEnvironmentFunc {
+	return func(key string) (string, bool) {
+		for _, source := range sources {
+			if v, ok := source(key); ok {
+				return v, ok
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,45 @@
+package environ
+
+import "testing"
+
+func TestPrefixLookuper(t *testing.T) {
+	base := MapLookuper(map[string]string{"DB_HOST": "localhost"})
+	lookup := PrefixLookuper("DB_", base)
+
+	if v, ok := lookup("HOST"); !ok || v != "localhost" {
+		t.Errorf("lookup(HOST) = %q, %v, want %q, true", v, ok, "localhost")
+	}
+	if _, ok := lookup("PORT"); ok {
+		t.Errorf("lookup(PORT) isset = true, want false")
+	}
+}
+
+func TestMapLookuper(t *testing.T) {
+	lookup := MapLookuper(map[string]string{"ABC": "def", "EMPTY": ""})
+
+	if v, ok := lookup("ABC"); !ok || v != "def" {
+		t.Errorf("lookup(ABC) = %q, %v, want %q, true", v, ok, "def")
+	}
+	if v, ok := lookup("EMPTY"); !ok || v != "" {
+		t.Errorf("lookup(EMPTY) = %q, %v, want %q, true", v, ok, "")
+	}
+	if _, ok := lookup("MISSING"); ok {
+		t.Errorf("lookup(MISSING) isset = true, want false")
+	}
+}
+
+func TestMultiLookuper(t *testing.T) {
+	first := MapLookuper(map[string]string{"ABC": "from-first"})
+	second := MapLookuper(map[string]string{"ABC": "from-second", "DEF": "from-second"})
+	lookup := MultiLookuper(first, second)
+
+	if v, ok := lookup("ABC"); !ok || v != "from-first" {
+		t.Errorf("lookup(ABC) = %q, %v, want %q, true", v, ok, "from-first")
+	}
+	if v, ok := lookup("DEF"); !ok || v != "from-second" {
+		t.Errorf("lookup(DEF) = %q, %v, want %q, true", v, ok, "from-second")
+	}
+	if _, ok := lookup("GHI"); ok {
+		t.Errorf("lookup(GHI) isset = true, want false")
+	}
+}
@@ -0,0 +1,99 @@
+package environ
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Mutator transforms a single looked-up environment value before it reaches
+// type conversion. Mutators are run in order by UnmarshalEnvironmentWith,
+// each seeing the previous one's output, and may turn an unset value into a
+// set one (or vice versa) as well as rewrite its contents.
+type Mutator func(ctx context.Context, key, value string, hasValue bool) (newValue string, newHasValue bool, err error)
+
+// runMutators feeds value/hasValue through mutators in order, stopping at
+// the first error.
+func runMutators(mutators []Mutator, key, value string, hasValue bool) (string, bool, error) {
+	ctx := context.Background()
+	for _, m := range mutators {
+		var err error
+		value, hasValue, err = m(ctx, key, value, hasValue)
+		if err != nil {
+			return "", false, err
+		}
+	}
+	return value, hasValue, nil
+}
+
+// TrimSpace is a Mutator which removes leading and trailing whitespace from
+// a set value. An unset value passes through unchanged.
+func TrimSpace(ctx context.Context, key, value string, hasValue bool) (string, bool, error) {
+	if !hasValue {
+		return value, hasValue, nil
+	}
+	return strings.TrimSpace(value), true, nil
+}
+
+// Base64Decode is a Mutator which replaces a set value with the result of
+// base64-decoding it, for variables whose value is supplied base64 encoded.
+func Base64Decode(ctx context.Context, key, value string, hasValue bool) (string, bool, error) {
+	if !hasValue {
+		return value, hasValue, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", false, fmt.Errorf("environ: base64 decoding %s: %w", key, err)
+	}
+	return string(decoded), true, nil
+}
+
+// fileLookuperPrefix marks a value which names a file to read from, rather
+// than being used directly.
+const fileLookuperPrefix = "file://"
+
+// FileLookuper returns a Mutator which substitutes a value with the
+// contents of a file, for secrets mounted into a container rather than set
+// directly. It applies when the value itself has a "file://" prefix, or
+// when the variable is unset but a companion KEY_FILE variable (queried
+// through lookupenv) is set.
+func FileLookuper(lookupenv LookupEnvironmentFunc) Mutator {
+	return func(ctx context.Context, key, value string, hasValue bool) (string, bool, error) {
+		path := ""
+		switch {
+		case hasValue && strings.HasPrefix(value, fileLookuperPrefix):
+			path = strings.TrimPrefix(value, fileLookuperPrefix)
+		case !hasValue:
+			if p, ok := lookupenv(key + "_FILE"); ok {
+				path = p
+			}
+		}
+		if path == "" {
+			return value, hasValue, nil
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("environ: reading %s for %s: %w", path, key, err)
+		}
+		return string(contents), true, nil
+	}
+}
+
+// ExpandLookuper returns a Mutator which expands "${OTHER_VAR}" references
+// in a set value by querying lookupenv for each referenced name, the same
+// way os.Expand works against os.LookupEnv. A referenced variable which
+// lookupenv reports as unset expands to an empty string.
+func ExpandLookuper(lookupenv LookupEnvironmentFunc) Mutator {
+	return func(ctx context.Context, key, value string, hasValue bool) (string, bool, error) {
+		if !hasValue {
+			return value, hasValue, nil
+		}
+		expanded := os.Expand(value, func(name string) string {
+			v, _ := lookupenv(name)
+			return v
+		})
+		return expanded, true, nil
+	}
+}
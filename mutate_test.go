@@ -0,0 +1,141 @@
+package environ
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrimSpace(t *testing.T) {
+	v, ok, err := TrimSpace(context.Background(), "KEY", "  hello  ", true)
+	if err != nil || !ok || v != "hello" {
+		t.Errorf("TrimSpace() = %q, %v, %v, want %q, true, nil", v, ok, err, "hello")
+	}
+
+	v, ok, err = TrimSpace(context.Background(), "KEY", "", false)
+	if err != nil || ok || v != "" {
+		t.Errorf("TrimSpace() on unset value = %q, %v, %v, want \"\", false, nil", v, ok, err)
+	}
+}
+
+func TestBase64Decode(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("hello"))
+
+	v, ok, err := Base64Decode(context.Background(), "KEY", encoded, true)
+	if err != nil || !ok || v != "hello" {
+		t.Errorf("Base64Decode() = %q, %v, %v, want %q, true, nil", v, ok, err, "hello")
+	}
+
+	if _, _, err := Base64Decode(context.Background(), "KEY", "not-base64!", true); err == nil {
+		t.Error("Base64Decode() error = nil, want an error for invalid input")
+	}
+}
+
+func TestExpandLookuper(t *testing.T) {
+	lookup := MapLookuper(map[string]string{"OTHER": "world"})
+	expand := ExpandLookuper(lookup)
+
+	v, ok, err := expand(context.Background(), "KEY", "hello ${OTHER}", true)
+	if err != nil || !ok || v != "hello world" {
+		t.Errorf("ExpandLookuper() = %q, %v, %v, want %q, true, nil", v, ok, err, "hello world")
+	}
+
+	v, ok, err = expand(context.Background(), "KEY", "hello ${MISSING}", true)
+	if err != nil || !ok || v != "hello " {
+		t.Errorf("ExpandLookuper() with unset reference = %q, %v, %v, want %q, true, nil", v, ok, err, "hello ")
+	}
+}
+
+func TestFileLookuper(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret")
+	if err := os.WriteFile(path, []byte("s3cr3t"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Run("reads a file:// prefixed value", func(t *testing.T) {
+		lookup := FileLookuper(MapLookuper(nil))
+		v, ok, err := lookup(context.Background(), "PASSWORD", "file://"+path, true)
+		if err != nil || !ok || v != "s3cr3t" {
+			t.Errorf("FileLookuper() = %q, %v, %v, want %q, true, nil", v, ok, err, "s3cr3t")
+		}
+	})
+
+	t.Run("reads the path from a companion KEY_FILE variable", func(t *testing.T) {
+		lookup := FileLookuper(MapLookuper(map[string]string{"PASSWORD_FILE": path}))
+		v, ok, err := lookup(context.Background(), "PASSWORD", "", false)
+		if err != nil || !ok || v != "s3cr3t" {
+			t.Errorf("FileLookuper() = %q, %v, %v, want %q, true, nil", v, ok, err, "s3cr3t")
+		}
+	})
+
+	t.Run("passes through an unset value with no companion variable", func(t *testing.T) {
+		lookup := FileLookuper(MapLookuper(nil))
+		v, ok, err := lookup(context.Background(), "PASSWORD", "", false)
+		if err != nil || ok || v != "" {
+			t.Errorf("FileLookuper() = %q, %v, %v, want \"\", false, nil", v, ok, err)
+		}
+	})
+
+	t.Run("wraps a read failure", func(t *testing.T) {
+		lookup := FileLookuper(MapLookuper(nil))
+		if _, _, err := lookup(context.Background(), "PASSWORD", "file:///does/not/exist", true); err == nil {
+			t.Error("FileLookuper() error = nil, want an error for a missing file")
+		}
+	})
+}
+
+func TestUnmarshalEnvironmentWith(t *testing.T) {
+	type creds struct {
+		Password string `environ:"PASSWORD"`
+		Greeting string `environ:"GREETING"`
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "password")
+	if err := os.WriteFile(path, []byte("  s3cr3t  "), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	lookup := MapLookuper(map[string]string{
+		"PASSWORD": "file://" + path,
+		"GREETING": "hello ${NAME}",
+		"NAME":     "world",
+	})
+
+	got := creds{}
+	err := UnmarshalEnvironmentWith(lookup, &got,
+		FileLookuper(lookup),
+		ExpandLookuper(lookup),
+		TrimSpace,
+	)
+	if err != nil {
+		t.Fatalf("UnmarshalEnvironmentWith() error = %v", err)
+	}
+	want := creds{Password: "s3cr3t", Greeting: "hello world"}
+	if got != want {
+		t.Errorf("UnmarshalEnvironmentWith() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalEnvironmentWithStopsOnMutatorError(t *testing.T) {
+	type creds struct {
+		Password string `environ:"PASSWORD"`
+	}
+	failing := func(ctx context.Context, key, value string, hasValue bool) (string, bool, error) {
+		return "", false, errors.New("boom")
+	}
+
+	got := creds{}
+	err := UnmarshalEnvironmentWith(MapLookuper(map[string]string{"PASSWORD": "x"}), &got, failing)
+	var fe *FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("UnmarshalEnvironmentWith() error = %v, want *FieldError", err)
+	}
+	if fe.Field != "Password" || fe.EnvironmentVariable != "PASSWORD" {
+		t.Errorf("FieldError = %+v, want Field=Password EnvironmentVariable=PASSWORD", fe)
+	}
+}
@@ -0,0 +1,113 @@
+package environ
+
+import (
+	"errors"
+	"testing"
+)
+
+type nestedDBConfig struct {
+	Host string `environ:"HOST,required"`
+	Port int    `environ:"PORT,default=5432"`
+}
+
+type nestedAppConfig struct {
+	Name  string          `environ:"NAME"`
+	DB    nestedDBConfig  `environ:",prefix=DB_"`
+	Cache *nestedDBConfig `environ:",prefix=CACHE_"`
+}
+
+func TestUnmarshalEnvironmentNestedStruct(t *testing.T) {
+	t.Run("recurses into a non-pointer struct field", func(t *testing.T) {
+		got := nestedAppConfig{}
+		err := UnmarshalEnvironment(MapLookuper(map[string]string{
+			"NAME":       "myapp",
+			"DB_HOST":    "db.internal",
+			"CACHE_HOST": "cache.internal",
+		}), &got)
+		if err != nil {
+			t.Fatalf("UnmarshalEnvironment() error = %v", err)
+		}
+		want := nestedAppConfig{Name: "myapp", DB: nestedDBConfig{Host: "db.internal", Port: 5432}}
+		if got.Name != want.Name || got.DB != want.DB {
+			t.Errorf("got = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("allocates a nil pointer-to-struct field before recursing", func(t *testing.T) {
+		got := nestedAppConfig{}
+		err := UnmarshalEnvironment(MapLookuper(map[string]string{
+			"DB_HOST":    "db.internal",
+			"CACHE_HOST": "cache.internal",
+		}), &got)
+		if err != nil {
+			t.Fatalf("UnmarshalEnvironment() error = %v", err)
+		}
+		if got.Cache == nil {
+			t.Fatal("Cache was not allocated")
+		}
+		if got.Cache.Host != "cache.internal" {
+			t.Errorf("Cache.Host = %q, want %q", got.Cache.Host, "cache.internal")
+		}
+	})
+
+	t.Run("propagates a required field error from a nested struct", func(t *testing.T) {
+		got := nestedAppConfig{}
+		err := UnmarshalEnvironment(MapLookuper(map[string]string{
+			"CACHE_HOST": "cache.internal",
+		}), &got)
+		var ee EnvironErrors
+		if !errors.As(err, &ee) {
+			t.Fatalf("UnmarshalEnvironment() error = %v, want EnvironErrors", err)
+		}
+		if want := []string{"HOST"}; len(ee.Missing()) != 1 || ee.Missing()[0] != want[0] {
+			t.Errorf("EnvironErrors.Missing() = %v, want %v", ee.Missing(), want)
+		}
+	})
+}
+
+// cycleA and cycleB refer to each other so that recursing into either one
+// revisits the same struct type, proving the cycle detector stops it
+// instead of recursing forever.
+type cycleA struct {
+	B *cycleB `environ:",prefix=B_"`
+}
+
+type cycleB struct {
+	A *cycleA `environ:",prefix=A_"`
+}
+
+func TestUnmarshalEnvironmentCycleDetection(t *testing.T) {
+	got := cycleA{}
+	err := UnmarshalEnvironment(MapLookuper(map[string]string{}), &got)
+	if err == nil {
+		t.Fatal("UnmarshalEnvironment() error = nil, want a cycle error")
+	}
+}
+
+// trapDoor is a type which takes over its own unmarshalling, proving that
+// UnmarshalEnviron is called instead of the struct being walked field by
+// field.
+type trapDoor struct {
+	Value string
+}
+
+func (t *trapDoor) UnmarshalEnviron(lookupenv LookupEnvironmentFunc) error {
+	v, _ := lookupenv("VALUE")
+	t.Value = "decoded:" + v
+	return nil
+}
+
+type trapDoorHolder struct {
+	Door trapDoor `environ:",prefix=DOOR_"`
+}
+
+func TestUnmarshalEnvironmentUnmarshaler(t *testing.T) {
+	got := trapDoorHolder{}
+	err := UnmarshalEnvironment(MapLookuper(map[string]string{"DOOR_VALUE": "hello"}), &got)
+	if err != nil {
+		t.Fatalf("UnmarshalEnvironment() error = %v", err)
+	}
+	if got.Door.Value != "decoded:hello" {
+		t.Errorf("Door.Value = %q, want %q", got.Door.Value, "decoded:hello")
+	}
+}
@@ -0,0 +1,224 @@
+package environ
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// RenderFormat selects how Render writes out a struct's fields.
+type RenderFormat int
+
+const (
+	// RenderPlain writes "{ Field:value Field:value }", the format
+	// previously produced by the now-removed ToString.
+	RenderPlain RenderFormat = iota
+	// RenderDotenv writes one "VARIABLE=value" line per field, suitable
+	// for a .env file.
+	RenderDotenv
+	// RenderJSON writes the fields as a JSON object keyed by environment
+	// variable name. Each value is marshalled as its own Go type (numbers,
+	// booleans, slices, and so on stay typed), except a "redact" field,
+	// which is always written out as displayValue's masked string.
+	RenderJSON
+	// RenderUsage writes a table of every recognised variable: its Go
+	// type, whether it's required, its default (if any), and whether its
+	// current value came from the environment, a default, or is unset.
+	RenderUsage
+)
+
+// RenderOption configures a call to Render.
+type RenderOption func(*renderConfig)
+
+type renderConfig struct {
+	format    RenderFormat
+	lookupenv LookupEnvironmentFunc
+}
+
+// WithRenderFormat sets the output format. The default is RenderPlain.
+func WithRenderFormat(format RenderFormat) RenderOption {
+	return func(c *renderConfig) { c.format = format }
+}
+
+// WithRenderLookup supplies the LookupEnvironmentFunc used by RenderUsage to
+// report each variable's current source. It has no effect on other formats.
+// The default is os.LookupEnv.
+func WithRenderLookup(lookupenv LookupEnvironmentFunc) RenderOption {
+	return func(c *renderConfig) { c.lookupenv = lookupenv }
+}
+
+// renderField is one leaf, non-struct field collected by
+// collectRenderFields, flattened out of any nested structs it came from.
+type renderField struct {
+	Name  string
+	Value reflect.Value
+	StateVar
+}
+
+// Render formats es, a struct or pointer to one, as described by opts. The
+// default format is RenderPlain.
+//
+// Unlike the old ToString, Render dereferences pointers, recurses into
+// nested struct fields the same way UnmarshalEnvironment does (honouring
+// "prefix="), and redacts a "redact" tagged field's value regardless of its
+// type rather than only strings.
+func Render(es interface{}, opts ...RenderOption) (string, error) {
+	cfg := renderConfig{format: RenderPlain, lookupenv: os.LookupEnv}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	rv := reflect.ValueOf(es)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", fmt.Errorf("environ: Render of nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return "", fmt.Errorf("environ: Render of non-struct %s", rv.Type())
+	}
+	if !rv.CanAddr() {
+		// Copy into an addressable value so that, like UnmarshalEnvironment,
+		// pointer-receiver Decoder/TextUnmarshaler implementations are
+		// detected even when es was passed by value.
+		addr := reflect.New(rv.Type())
+		addr.Elem().Set(rv)
+		rv = addr.Elem()
+	}
+
+	fields, err := collectRenderFields("", rv)
+	if err != nil {
+		return "", err
+	}
+
+	switch cfg.format {
+	case RenderDotenv:
+		return renderDotenv(fields), nil
+	case RenderJSON:
+		return renderJSON(fields)
+	case RenderUsage:
+		return renderUsage(fields, cfg.lookupenv), nil
+	default:
+		return renderPlain(fields), nil
+	}
+}
+
+// collectRenderFields walks rv's fields, recursing into nested structs
+// (applying their "prefix=" the same way unmarshalStruct does) and
+// flattening the result into a single list of leaf fields. A nil
+// pointer-to-struct field is skipped rather than recursed into, since there
+// is no data to report for it.
+func collectRenderFields(prefix string, rv reflect.Value) ([]renderField, error) {
+	st := rv.Type()
+	var fields []renderField
+	for i := 0; i < st.NumField(); i++ {
+		svf := st.Field(i)
+		stv, err := parseStateVar(svf.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		fv := rv.Field(i)
+		isPtrToStruct := svf.Type.Kind() == reflect.Ptr && svf.Type.Elem().Kind() == reflect.Struct
+		isStruct := svf.Type.Kind() == reflect.Struct
+		if !hasCustomDecoder(fv) && (isPtrToStruct || isStruct) {
+			if isPtrToStruct {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			nested, err := collectRenderFields(prefix+stv.Prefix, fv)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+
+		stv.EnvironmentVariable = prefix + stv.EnvironmentVariable
+		fields = append(fields, renderField{Name: svf.Name, Value: fv, StateVar: stv})
+	}
+	return fields, nil
+}
+
+// displayValue returns f's value as a string, masking it down to
+// "********" (or "" for a zero value) if it's tagged "redact".
+func (f renderField) displayValue() string {
+	if f.Redact {
+		if f.Value.IsZero() {
+			return ""
+		}
+		return "********"
+	}
+	if f.Value.Kind() == reflect.Ptr && f.Value.IsNil() {
+		return fmt.Sprintf("%v", f.Value.Interface())
+	}
+	if str, ok := f.Value.Interface().(fmt.Stringer); ok {
+		return str.String()
+	}
+	return fmt.Sprintf("%v", f.Value.Interface())
+}
+
+func renderPlain(fields []renderField) string {
+	sb := strings.Builder{}
+	sb.WriteString("{ ")
+	for _, f := range fields {
+		sb.WriteString(fmt.Sprintf("%s:%s ", f.Name, f.displayValue()))
+	}
+	sb.WriteRune('}')
+	return sb.String()
+}
+
+func renderDotenv(fields []renderField) string {
+	lines := make([]string, len(fields))
+	for i, f := range fields {
+		lines[i] = fmt.Sprintf("%s=%s", f.EnvironmentVariable, f.displayValue())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderJSON marshals fields keyed by environment variable name. A
+// non-redacted field is marshalled as its own Go value, so RenderJSON
+// produces typed JSON (numbers, booleans, arrays) rather than stringifying
+// everything the way RenderPlain and RenderDotenv do. A "redact" field is
+// still written out through displayValue, so its masked string form is
+// what's marshalled, not the underlying value.
+func renderJSON(fields []renderField) (string, error) {
+	out := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		if f.Redact {
+			out[f.EnvironmentVariable] = f.displayValue()
+			continue
+		}
+		out[f.EnvironmentVariable] = f.Value.Interface()
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("environ: rendering JSON: %w", err)
+	}
+	return string(b), nil
+}
+
+func renderUsage(fields []renderField, lookupenv LookupEnvironmentFunc) string {
+	sb := strings.Builder{}
+	sb.WriteString("VARIABLE\tTYPE\tREQUIRED\tDEFAULT\tSOURCE\tVALUE\n")
+	for _, f := range fields {
+		def := "-"
+		if f.Default != nil {
+			def = *f.Default
+		}
+		source := "unset"
+		if _, isset := lookupenv(f.EnvironmentVariable); isset {
+			source = "env"
+		} else if f.Default != nil {
+			source = "default"
+		}
+		sb.WriteString(fmt.Sprintf("%s\t%s\t%t\t%s\t%s\t%s\n",
+			f.EnvironmentVariable, f.Value.Type(), f.Required, def, source, f.displayValue()))
+	}
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package environ
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRenderDereferencesPointer(t *testing.T) {
+	es := &TestEnv{Abc: "foo"}
+	got, err := Render(es)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "{ Abc:foo Def: Ghi:false Jkl:false Mno:0 Pqr:[] }"
+	if got != want {
+		t.Errorf("Render() = %v, want %v", got, want)
+	}
+}
+
+func TestRenderNestedStruct(t *testing.T) {
+	got, err := Render(nestedAppConfig{
+		Name: "myapp",
+		DB:   nestedDBConfig{Host: "db.internal", Port: 5432},
+	}, WithRenderFormat(RenderDotenv))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := "NAME=myapp\nDB_HOST=db.internal\nDB_PORT=5432"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderNestedStructSkipsNilPointer(t *testing.T) {
+	got, err := Render(nestedAppConfig{
+		Name: "myapp",
+		DB:   nestedDBConfig{Host: "db.internal", Port: 5432},
+	}, WithRenderFormat(RenderDotenv))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if strings.Contains(got, "CACHE_") {
+		t.Errorf("Render() = %q, want no CACHE_ lines for a nil Cache pointer", got)
+	}
+}
+
+// selfDescribing is a test-only type whose String method dereferences the
+// receiver, so calling it on a nil *selfDescribing would panic. It's not a
+// struct, so collectRenderFields can't skip a nil field the way it does for
+// nil pointer-to-struct fields.
+type selfDescribing string
+
+func (s *selfDescribing) String() string {
+	return "value:" + string(*s)
+}
+
+func TestRenderDoesNotPanicOnNilStringer(t *testing.T) {
+	type nilStringerEnv struct {
+		Label *selfDescribing `environ:"LABEL"`
+	}
+	got, err := Render(nilStringerEnv{}, WithRenderFormat(RenderDotenv))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got != "LABEL=<nil>" {
+		t.Errorf("Render() = %q, want %q", got, "LABEL=<nil>")
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	got, err := Render(TestEnv{Abc: "foo", Def: "secretz", Mno: 42}, WithRenderFormat(RenderJSON))
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &out); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if out["ABC"] != "foo" {
+		t.Errorf("out[ABC] = %v, want %q", out["ABC"], "foo")
+	}
+	if out["DEF"] != "********" {
+		t.Errorf("out[DEF] = %v, want %q", out["DEF"], "********")
+	}
+	if out["MNO"] != float64(42) {
+		t.Errorf("out[MNO] = %v (%T), want the typed number 42, not a string", out["MNO"], out["MNO"])
+	}
+}
+
+func TestRenderDoesNotAllocateNilDecoderPointer(t *testing.T) {
+	type nilDecoderEnv struct {
+		Timeout *textDuration `environ:"TIMEOUT"`
+	}
+	got := nilDecoderEnv{}
+	if _, err := Render(&got, WithRenderFormat(RenderDotenv)); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got.Timeout != nil {
+		t.Errorf("Render() allocated Timeout = %v, want it to stay nil", got.Timeout)
+	}
+}
+
+func TestRenderUsage(t *testing.T) {
+	got, err := Render(RequiredDefaultEnv{Host: "example.com"},
+		WithRenderFormat(RenderUsage),
+		WithRenderLookup(MapLookuper(map[string]string{"HOST": "example.com"})),
+	)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{"HOST", "string", "true", "env", "PORT", "default", "8080"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestRenderRejectsNonStruct(t *testing.T) {
+	if _, err := Render("not a struct"); err == nil {
+		t.Error("Render() error = nil, want an error for a non-struct value")
+	}
+}
@@ -0,0 +1,107 @@
+package environ
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalEnvironmentScalarTypes(t *testing.T) {
+	type scalars struct {
+		I8  int8          `environ:"I8"`
+		I16 int16         `environ:"I16"`
+		I32 int32         `environ:"I32"`
+		I64 int64         `environ:"I64"`
+		U   uint          `environ:"U"`
+		U8  uint8         `environ:"U8"`
+		U16 uint16        `environ:"U16"`
+		U32 uint32        `environ:"U32"`
+		U64 uint64        `environ:"U64"`
+		F32 float32       `environ:"F32"`
+		F64 float64       `environ:"F64"`
+		Dur time.Duration `environ:"DUR"`
+	}
+
+	got := scalars{}
+	err := UnmarshalEnvironment(MapLookuper(map[string]string{
+		"I8": "-8", "I16": "-16", "I32": "-32", "I64": "-64",
+		"U": "1", "U8": "8", "U16": "16", "U32": "32", "U64": "64",
+		"F32": "1.5", "F64": "2.5",
+		"DUR": "90s",
+	}), &got)
+	if err != nil {
+		t.Fatalf("UnmarshalEnvironment() error = %v", err)
+	}
+	want := scalars{
+		I8: -8, I16: -16, I32: -32, I64: -64,
+		U: 1, U8: 8, U16: 16, U32: 32, U64: 64,
+		F32: 1.5, F64: 2.5,
+		Dur: 90 * time.Second,
+	}
+	if got != want {
+		t.Errorf("got = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalEnvironmentSliceTypes(t *testing.T) {
+	type slices struct {
+		Strs  []string `environ:"STRS"`
+		Ints  []int    `environ:"INTS"`
+		Bytes []byte   `environ:"BYTES"`
+		Semi  []string `environ:"SEMI,separator=;"`
+	}
+
+	got := slices{}
+	err := UnmarshalEnvironment(MapLookuper(map[string]string{
+		"STRS":  "a,b,c",
+		"INTS":  "1,2,3",
+		"BYTES": "hello",
+		"SEMI":  "a;b;c",
+	}), &got)
+	if err != nil {
+		t.Fatalf("UnmarshalEnvironment() error = %v", err)
+	}
+	want := slices{
+		Strs:  []string{"a", "b", "c"},
+		Ints:  []int{1, 2, 3},
+		Bytes: []byte("hello"),
+		Semi:  []string{"a", "b", "c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalEnvironmentMapTypes(t *testing.T) {
+	type maps struct {
+		Strs map[string]string `environ:"STRS"`
+		Ints map[string]int    `environ:"INTS,kvseparator==,separator=;"`
+	}
+
+	got := maps{}
+	err := UnmarshalEnvironment(MapLookuper(map[string]string{
+		"STRS": "a:1,b:2",
+		"INTS": "a=1;b=2",
+	}), &got)
+	if err != nil {
+		t.Fatalf("UnmarshalEnvironment() error = %v", err)
+	}
+	want := maps{
+		Strs: map[string]string{"a": "1", "b": "2"},
+		Ints: map[string]int{"a": 1, "b": 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshalEnvironmentInvalidMapEntry(t *testing.T) {
+	type maps struct {
+		M map[string]string `environ:"M"`
+	}
+	got := maps{}
+	err := UnmarshalEnvironment(MapLookuper(map[string]string{"M": "no-colon-here"}), &got)
+	if err == nil {
+		t.Fatal("UnmarshalEnvironment() error = nil, want an invalid map entry error")
+	}
+}